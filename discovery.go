@@ -0,0 +1,272 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Endpoint describes one target read from a segi9.http.discovery /
+// segi9.http.bulk source file. Group is not part of the on-disk schema the
+// request asked for but is needed to answer "which endpoints does
+// segi9.http.bulk[group_name] mean"; an endpoint with no group is selected
+// by the empty group name.
+type Endpoint struct {
+	Name         string `json:"name"          yaml:"name"`
+	URL          string `json:"url"           yaml:"url"`
+	Group        string `json:"group"         yaml:"group"`
+	AuthType     string `json:"auth_type"     yaml:"auth_type"`
+	User         string `json:"user"          yaml:"user"`
+	Pass         string `json:"pass"          yaml:"pass"`
+	Method       string `json:"method"        yaml:"method"`
+	Headers      string `json:"headers"       yaml:"headers"`
+	IntervalHint int    `json:"interval_hint" yaml:"interval_hint"`
+}
+
+// discoveryFileCache caches one parsed endpoint file, invalidated by mtime so
+// segi9.http.discovery/bulk don't re-read and re-parse the file on every
+// check cycle for what is normally a slowly-changing list.
+type discoveryFileCache struct {
+	mu        sync.Mutex
+	modTime   time.Time
+	endpoints []Endpoint
+}
+
+var discoveryCache sync.Map // file path -> *discoveryFileCache
+
+// loadEndpoints resolves source into an endpoint list. An inline JSON array
+// (source starts with "[") is parsed directly and never cached, since the
+// caller already holds the full value in memory; anything else is treated as
+// a file path and cached by mtime.
+func loadEndpoints(source string) ([]Endpoint, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, fmt.Errorf("no endpoint source configured")
+	}
+
+	if strings.HasPrefix(source, "[") {
+		var endpoints []Endpoint
+		if err := json.Unmarshal([]byte(source), &endpoints); err != nil {
+			return nil, fmt.Errorf("failed to parse inline endpoint list: %w", err)
+		}
+		return endpoints, nil
+	}
+
+	return loadEndpointsFile(source)
+}
+
+func loadEndpointsFile(path string) ([]Endpoint, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat endpoint file %q: %w", path, err)
+	}
+
+	entry, _ := discoveryCache.LoadOrStore(path, &discoveryFileCache{})
+	cache := entry.(*discoveryFileCache)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if cache.modTime.Equal(info.ModTime()) && cache.endpoints != nil {
+		return cache.endpoints, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read endpoint file %q: %w", path, err)
+	}
+
+	endpoints, err := parseEndpointFile(path, raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse endpoint file %q: %w", path, err)
+	}
+
+	cache.modTime = info.ModTime()
+	cache.endpoints = endpoints
+	return endpoints, nil
+}
+
+// parseEndpointFile dispatches on the file extension, falling back to YAML
+// (a superset of JSON) for anything not recognised as .json.
+func parseEndpointFile(path string, raw []byte) ([]Endpoint, error) {
+	var endpoints []Endpoint
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return endpoints, json.Unmarshal(raw, &endpoints)
+	}
+	return endpoints, yaml.Unmarshal(raw, &endpoints)
+}
+
+// exportDiscovery implements segi9.http.discovery[<file>]. file is optional;
+// when omitted, Plugins.Segi9.Discovery.File is used instead.
+func (p *Plugin) exportDiscovery(source string) (interface{}, error) {
+	if source == "" {
+		p.mu.RLock()
+		source = p.config.Discovery.File
+		p.mu.RUnlock()
+	}
+
+	endpoints, err := loadEndpoints(source)
+	if err != nil {
+		return nil, fmt.Errorf("segi9.http.discovery: %w", err)
+	}
+
+	data := make([]map[string]string, 0, len(endpoints))
+	for _, ep := range endpoints {
+		data = append(data, map[string]string{
+			"{#NAME}": ep.Name,
+			"{#URL}":  ep.URL,
+		})
+	}
+
+	lld, err := json.Marshal(map[string]interface{}{"data": data})
+	if err != nil {
+		return nil, fmt.Errorf("segi9.http.discovery: failed to render LLD JSON: %w", err)
+	}
+	return string(lld), nil
+}
+
+// bulkResult is one endpoint's outcome in the segi9.http.bulk[group_name]
+// response map. BodyHash lets a trigger fire on "content changed" without
+// shipping every endpoint's full body through Zabbix.
+type bulkResult struct {
+	Status     int    `json:"status"`
+	BodyHash   string `json:"body_hash,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// exportBulk implements segi9.http.bulk[<group_name>]. It always reads the
+// configured Plugins.Segi9.Discovery.File — the item key's one parameter
+// selects a group out of it, not a path. An empty group_name selects
+// endpoints with no group set.
+func (p *Plugin) exportBulk(groupName string) (interface{}, error) {
+	p.mu.RLock()
+	source := p.config.Discovery.File
+	maxConcurrency := p.config.MaxConcurrency
+	p.mu.RUnlock()
+
+	if source == "" {
+		return nil, fmt.Errorf("segi9.http.bulk: Plugins.Segi9.Discovery.File is not configured")
+	}
+
+	endpoints, err := loadEndpoints(source)
+	if err != nil {
+		return nil, fmt.Errorf("segi9.http.bulk: %w", err)
+	}
+
+	var selected []Endpoint
+	for _, ep := range endpoints {
+		if ep.Group == groupName {
+			selected = append(selected, ep)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("segi9.http.bulk: no endpoints in group %q", groupName)
+	}
+
+	if maxConcurrency < 1 {
+		maxConcurrency = 5
+	}
+
+	results := make(map[string]bulkResult, len(selected))
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, maxConcurrency)
+	)
+
+	for _, ep := range selected {
+		wg.Add(1)
+		go func(ep Endpoint) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			res := p.fetchBulkEndpoint(ep)
+
+			mu.Lock()
+			results[ep.Name] = res
+			mu.Unlock()
+		}(ep)
+	}
+	wg.Wait()
+
+	out, err := json.Marshal(results)
+	if err != nil {
+		return nil, fmt.Errorf("segi9.http.bulk: failed to render result map: %w", err)
+	}
+	return string(out), nil
+}
+
+// fetchBulkEndpoint performs one endpoint's request for segi9.http.bulk. It
+// deliberately skips the oauth2/oidc and body-encoding machinery in
+// doRequest — bulk checks are meant for lightweight status/hash polling
+// across many endpoints, not the full segi9.http feature set.
+func (p *Plugin) fetchBulkEndpoint(ep Endpoint) bulkResult {
+	start := time.Now()
+
+	p.mu.RLock()
+	timeout := time.Duration(p.config.Timeout) * time.Second
+	transport := p.transport
+	p.mu.RUnlock()
+
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	if transport == nil {
+		transport, _ = buildTargetTransport(p.config)
+	}
+	client := &http.Client{Timeout: timeout, Transport: transport}
+
+	method, err := parseHTTPMethod(ep.Method)
+	if err != nil {
+		return bulkResult{Error: err.Error()}
+	}
+
+	headers, err := parseHeaders(ep.Headers)
+	if err != nil {
+		return bulkResult{Error: err.Error()}
+	}
+
+	req, err := http.NewRequest(method, ep.URL, nil)
+	if err != nil {
+		return bulkResult{Error: fmt.Sprintf("failed to build request: %v", err)}
+	}
+	for _, h := range headers {
+		req.Header.Set(h[0], h[1])
+	}
+
+	switch strings.ToLower(ep.AuthType) {
+	case "basic":
+		req.SetBasicAuth(ep.User, ep.Pass)
+	case "bearer":
+		req.Header.Set("Authorization", "Bearer "+ep.User)
+	case "", "none":
+		// No authentication.
+	default:
+		return bulkResult{Error: fmt.Sprintf("unsupported auth_type %q", ep.AuthType)}
+	}
+
+	resp, body, err := execRequest(client, req)
+	duration := time.Since(start)
+	if err != nil {
+		return bulkResult{DurationMS: duration.Milliseconds(), Error: err.Error()}
+	}
+
+	sum := sha256.Sum256(body)
+	return bulkResult{
+		Status:     resp.StatusCode,
+		BodyHash:   hex.EncodeToString(sum[:]),
+		DurationMS: duration.Milliseconds(),
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// validHTTPMethods are the methods segi9.http accepts in its method
+// parameter. Anything else is rejected with a clear error rather than being
+// passed through to net/http, which would otherwise happily send a bogus
+// verb to the target server.
+var validHTTPMethods = map[string]bool{
+	"GET":    true,
+	"POST":   true,
+	"PUT":    true,
+	"PATCH":  true,
+	"DELETE": true,
+	"HEAD":   true,
+}
+
+// parseHTTPMethod normalises the method parameter, defaulting to GET for
+// backward compatibility with item keys that predate this parameter.
+func parseHTTPMethod(raw string) (string, error) {
+	raw = strings.ToUpper(strings.TrimSpace(raw))
+	if raw == "" {
+		return "GET", nil
+	}
+	if !validHTTPMethods[raw] {
+		return "", fmt.Errorf(
+			"unsupported method %q; valid values are: GET, POST, PUT, PATCH, DELETE, HEAD",
+			raw,
+		)
+	}
+	return raw, nil
+}
+
+// decodeRequestBody returns the raw bytes to send as the request body. A
+// "b64:" prefix marks the remainder as base64-encoded, letting the body
+// parameter carry arbitrary binary payloads through a text item key.
+func decodeRequestBody(raw string) ([]byte, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	if rest, ok := strings.CutPrefix(raw, "b64:"); ok {
+		decoded, err := base64.StdEncoding.DecodeString(rest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to base64-decode body: %w", err)
+		}
+		return decoded, nil
+	}
+	return []byte(raw), nil
+}
+
+// parseHeaders splits a "Key: Value" list separated by newlines and/or "|"
+// into ordered key/value pairs. Blank entries are ignored so both
+// "A: 1|B: 2" and a multi-line textarea-style value work.
+func parseHeaders(raw string) ([][2]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	raw = strings.ReplaceAll(raw, "|", "\n")
+
+	var headers [][2]string
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header entry %q; expected \"Key: Value\"", line)
+		}
+
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if name == "" {
+			return nil, fmt.Errorf("invalid header entry %q; header name is empty", line)
+		}
+
+		headers = append(headers, [2]string{name, value})
+	}
+
+	return headers, nil
+}
+
+// validReturnModes are the return_mode values segi9.http accepts.
+var validReturnModes = map[string]bool{
+	"":            true, // defaults to body
+	"body":        true,
+	"status":      true,
+	"duration_ms": true,
+	"header":      true,
+	"jsonpath":    true,
+	"regex":       true,
+}
+
+// validateReturnMode checks return_mode is known and that modes requiring
+// an expr parameter (header name, JSONPath, or regex pattern) got one.
+func validateReturnMode(mode, expr string) error {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if !validReturnModes[mode] {
+		return fmt.Errorf(
+			"unsupported return_mode %q; valid values are: body, status, duration_ms, header, jsonpath, regex",
+			mode,
+		)
+	}
+
+	switch mode {
+	case "header", "jsonpath", "regex":
+		if strings.TrimSpace(expr) == "" {
+			return fmt.Errorf("return_mode %q requires the expr parameter", mode)
+		}
+	}
+
+	return nil
+}
+
+// parseTimeoutOverride parses the per-call timeout parameter in seconds,
+// clamped to the same [1..30] range as Plugins.Segi9.Timeout. An empty
+// string means "no override" and is reported via ok=false.
+func parseTimeoutOverride(raw string) (seconds int, ok bool, err error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, false, nil
+	}
+
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid timeout %q: must be an integer number of seconds", raw)
+	}
+
+	if n < 1 {
+		n = 1
+	}
+	if n > 30 {
+		n = 30
+	}
+	return n, true, nil
+}
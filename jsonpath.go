@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// extractJSONPath evaluates a small vendored JSONPath subset against a JSON
+// response body and renders the matched value as a string. Supported
+// syntax, enough for picking a scalar out of a typical REST response
+// without pulling in a full JSONPath dependency:
+//
+//	.field               – object member access
+//	[n]                  – array index
+//	[?(@.field=='val')]  – first array element whose field equals val
+//
+// A leading "$" is optional and stripped, matching common JSONPath usage.
+func extractJSONPath(body []byte, path string) (string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", fmt.Errorf("jsonpath: response is not valid JSON: %w", err)
+	}
+
+	result, err := applyJSONPath(doc, path)
+	if err != nil {
+		return "", fmt.Errorf("jsonpath %q: %w", path, err)
+	}
+
+	return jsonScalarToString(result)
+}
+
+var (
+	jsonPathSegmentRe = regexp.MustCompile(`^([^\[\]]*)((?:\[[^\]]*\])*)$`)
+	jsonPathBracketRe = regexp.MustCompile(`\[([^\]]*)\]`)
+	jsonPathFilterRe  = regexp.MustCompile(`^\?\(@\.([A-Za-z0-9_]+)\s*==\s*'([^']*)'\)$`)
+)
+
+// applyJSONPath walks doc one dot-separated segment at a time, applying any
+// [index] or [?(@.field=='val')] brackets attached to each segment.
+func applyJSONPath(doc interface{}, path string) (interface{}, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return doc, nil
+	}
+
+	// Split on dots outside brackets only, so the "@." inside a filter
+	// stays part of its segment.
+	var segs []string
+	depth, segStart := 0, 0
+	for i, c := range path {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '.':
+			if depth == 0 {
+				segs = append(segs, path[segStart:i])
+				segStart = i + 1
+			}
+		}
+	}
+	segs = append(segs, path[segStart:])
+
+	cur := doc
+	for _, seg := range segs {
+		if seg == "" {
+			continue
+		}
+
+		m := jsonPathSegmentRe.FindStringSubmatch(seg)
+		if m == nil {
+			return nil, fmt.Errorf("invalid path segment %q", seg)
+		}
+		field, brackets := m[1], m[2]
+
+		if field != "" {
+			obj, ok := cur.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot access field %q on a non-object", field)
+			}
+			val, ok := obj[field]
+			if !ok {
+				return nil, fmt.Errorf("field %q not found", field)
+			}
+			cur = val
+		}
+
+		for _, bm := range jsonPathBracketRe.FindAllStringSubmatch(brackets, -1) {
+			var err error
+			cur, err = applyJSONPathBracket(cur, strings.TrimSpace(bm[1]))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return cur, nil
+}
+
+// applyJSONPathBracket applies one [index] or [?(@.field=='val')] bracket to
+// the current array value.
+func applyJSONPathBracket(cur interface{}, expr string) (interface{}, error) {
+	arr, ok := cur.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cannot index a non-array with [%s]", expr)
+	}
+
+	if strings.HasPrefix(expr, "?(") {
+		m := jsonPathFilterRe.FindStringSubmatch(expr)
+		if m == nil {
+			return nil, fmt.Errorf("unsupported filter [%s]; only [?(@.field=='value')] is supported", expr)
+		}
+		key, want := m[1], m[2]
+
+		for _, item := range arr {
+			obj, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if got, ok := obj[key]; ok && fmt.Sprintf("%v", got) == want {
+				return item, nil
+			}
+		}
+		return nil, fmt.Errorf("no array element matches filter [%s]", expr)
+	}
+
+	idx, err := strconv.Atoi(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid array index %q", expr)
+	}
+	if idx < 0 || idx >= len(arr) {
+		return nil, fmt.Errorf("array index %d out of range (len=%d)", idx, len(arr))
+	}
+	return arr[idx], nil
+}
+
+// jsonScalarToString renders a matched JSONPath value for return from
+// Export: scalars print as themselves, objects/arrays fall back to their
+// JSON encoding.
+func jsonScalarToString(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return val, nil
+	case bool, float64:
+		return fmt.Sprintf("%v", val), nil
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return "", fmt.Errorf("failed to render matched value: %w", err)
+		}
+		return string(b), nil
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import "testing"
+
+func TestExtractJSONPath(t *testing.T) {
+	body := []byte(`{
+		"status": "ok",
+		"count": 3,
+		"nested": {"name": "widget"},
+		"items": [
+			{"id": 1, "name": "a"},
+			{"id": 2, "name": "b"}
+		]
+	}`)
+
+	tests := []struct {
+		name    string
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{name: "top-level field", path: ".status", want: "ok"},
+		{name: "leading $ is stripped", path: "$.status", want: "ok"},
+		{name: "number renders as a scalar", path: ".count", want: "3"},
+		{name: "nested field", path: ".nested.name", want: "widget"},
+		{name: "array index", path: ".items[0].name", want: "a"},
+		{name: "array filter", path: ".items[?(@.id=='2')].name", want: "b"},
+		{name: "missing field", path: ".missing", wantErr: true},
+		{name: "index on a non-array", path: ".nested[0]", wantErr: true},
+		{name: "index out of range", path: ".items[5]", wantErr: true},
+		{name: "filter with no match", path: ".items[?(@.id=='99')]", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractJSONPath(body, tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("extractJSONPath(%q) = %q, want an error", tt.path, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractJSONPath(%q) returned error: %v", tt.path, err)
+			}
+			if got != tt.want {
+				t.Fatalf("extractJSONPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractJSONPathInvalidBody(t *testing.T) {
+	if _, err := extractJSONPath([]byte("not json"), ".status"); err == nil {
+		t.Fatal("expected an error for a non-JSON body")
+	}
+}
+
+func TestExtractJSONPathObjectResult(t *testing.T) {
+	body := []byte(`{"nested": {"name": "widget"}}`)
+	got, err := extractJSONPath(body, ".nested")
+	if err != nil {
+		t.Fatalf("extractJSONPath returned error: %v", err)
+	}
+	if got != `{"name":"widget"}` {
+		t.Fatalf("extractJSONPath(.nested) = %q, want the JSON-encoded object", got)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// logLevel is an ordinal log severity, used both for filtering via
+// Plugins.Segi9.Logging.Level and for mapping onto the Zabbix agent's own
+// Infof/Debugf/Errf levels.
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+func parseLogLevel(s string) logLevel {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return levelDebug
+	case "warn", "warning":
+		return levelWarn
+	case "error":
+		return levelError
+	default:
+		return levelInfo
+	}
+}
+
+func (l logLevel) String() string {
+	switch l {
+	case levelDebug:
+		return "debug"
+	case levelWarn:
+		return "warn"
+	case levelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// field is one structured key/value pair attached to a log event. A slice
+// instead of a map keeps rendering order stable and avoids an allocation for
+// the common case of zero or one field.
+type field struct {
+	key   string
+	value interface{}
+}
+
+func f(key string, value interface{}) field { return field{key: key, value: value} }
+
+// Well-known field keys shared by Export/doRequest so the same piece of
+// context always lands under the same name regardless of log format.
+const (
+	fieldURL        = "url"
+	fieldAuthType   = "auth_type"
+	fieldStatus     = "status"
+	fieldBytes      = "bytes"
+	fieldDurationMS = "duration_ms"
+	fieldKey        = "key"
+	fieldItemID     = "item_id"
+)
+
+// logEvent renders and emits one structured log record at the given level.
+//
+// Under the Zabbix agent (p.Logger != nil) the record is rendered as a
+// single line and forwarded through p.Logger at the mapped level, so the
+// agent's own log file and verbosity settings keep working unchanged. In
+// manual mode the record is written directly to logOutput(), which is
+// stderr: manual mode does not call setupPluginLogging, so it never opens
+// the rotating lumberjack file.
+func (p *Plugin) logEvent(level logLevel, msg string, fields ...field) {
+	p.mu.RLock()
+	minLevel := parseLogLevel(p.config.Logging.Level)
+	format := p.config.Logging.Format
+	p.mu.RUnlock()
+
+	if level < minLevel {
+		return
+	}
+
+	line := renderLogLine(format, level, msg, fields)
+
+	if p.Logger != nil {
+		switch level {
+		case levelDebug:
+			p.Logger.Debugf("%s", line)
+		case levelError:
+			p.Logger.Errf("%s", line)
+		default:
+			p.Logger.Infof("%s", line)
+		}
+		return
+	}
+
+	fmt.Fprintln(logOutput(), line)
+}
+
+// logOutput returns the writer used when p.Logger is nil: the rotating
+// lumberjack file if setupPluginLogging (plugin mode only) opened one,
+// stderr otherwise. Manual mode does not open the file and always gets
+// stderr.
+func logOutput() io.Writer {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if logWriter != nil {
+		return logWriter
+	}
+	return os.Stderr
+}
+
+// renderLogLine formats one event according to Plugins.Segi9.Logging.Format.
+// "text" (the default) matches the plugin's historical [LEVEL] prefix style;
+// "json" and "logfmt" are for shipping to Loki/ELK style log pipelines.
+func renderLogLine(format string, level logLevel, msg string, fields []field) string {
+	switch strings.ToLower(format) {
+	case "json":
+		return renderJSON(level, msg, fields)
+	case "logfmt":
+		return renderLogfmt(level, msg, fields)
+	default:
+		return renderText(level, msg, fields)
+	}
+}
+
+func renderText(level logLevel, msg string, fields []field) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%s] %s", strings.ToUpper(level.String()), msg)
+	for _, fld := range fields {
+		fmt.Fprintf(&b, " %s=%v", fld.key, fld.value)
+	}
+	return b.String()
+}
+
+func renderLogfmt(level logLevel, msg string, fields []field) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "time=%s level=%s msg=%q", time.Now().Format(time.RFC3339), level.String(), msg)
+	for _, fld := range fields {
+		fmt.Fprintf(&b, " %s=%s", fld.key, logfmtValue(fld.value))
+	}
+	return b.String()
+}
+
+func logfmtValue(v interface{}) string {
+	s := fmt.Sprintf("%v", v)
+	if strings.ContainsAny(s, " \t\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+func renderJSON(level logLevel, msg string, fields []field) string {
+	rec := make(map[string]interface{}, len(fields)+3)
+	rec["time"] = time.Now().Format(time.RFC3339)
+	rec["level"] = level.String()
+	rec["msg"] = msg
+	for _, fld := range fields {
+		rec[fld.key] = fld.value
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		// Never lose the line over a marshal error — fall back to text.
+		return renderText(level, msg, fields)
+	}
+	return string(b)
+}
@@ -5,11 +5,45 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+
+	"gopkg.in/natefinch/lumberjack.v2"
 
 	"golang.zabbix.com/sdk/plugin/container"
 )
 
+// logWriter is the shared rotating log writer used for plugin mode, or nil
+// when logging goes straight to stderr (no SEGI9_LOG_FILE configured).
+// logMu guards the logWriter pointer itself; configureLogRotation swaps in a
+// newly-constructed *lumberjack.Logger rather than mutating the fields of
+// whichever instance is live, since lumberjack.Logger.Write locks its own
+// unexported mutex that logMu has no relationship to.
+var (
+	logWriter *lumberjack.Logger
+	logMu     sync.Mutex
+)
+
+// rotatingWriter is the stable io.Writer registered with log.SetOutput for
+// plugin mode. It looks up the current logWriter under logMu on every
+// Write, so configureLogRotation can swap the pointer without racing a
+// concurrent write through the old instance.
+type rotatingWriter struct{}
+
+func (rotatingWriter) Write(p []byte) (int, error) {
+	logMu.Lock()
+	lw := logWriter
+	logMu.Unlock()
+
+	if lw == nil {
+		return os.Stderr.Write(p)
+	}
+	return lw.Write(p)
+}
+
 func main() {
 	// First check for Plugin Mode (socket path passed as first arg)
 	// This must be fast and side-effect free.
@@ -27,6 +61,7 @@ func runPlugin() {
 	// We default to stderr (which Zabbix captures).
 	// Only use file logging if explicitly requested via env var.
 	setupPluginLogging()
+	watchRotateSignal()
 
 	// Handle socket cleanup if necessary
 	socket := os.Args[1]
@@ -92,24 +127,127 @@ func runManual() {
 }
 
 func setupPluginLogging() {
-	log.SetOutput(os.Stderr)
+	// rotatingWriter falls back to stderr on its own as long as logWriter is
+	// nil, so this can be set unconditionally before we know whether
+	// SEGI9_LOG_FILE is even configured.
+	log.SetOutput(rotatingWriter{})
 
 	logPath := os.Getenv("SEGI9_LOG_FILE")
 	if logPath == "" {
 		return
 	}
 
-	// Try to open the log file. If it fails or blocks, we fallback to stderr.
-	// We do this synchronously but with a quick check if possible?
-	// Standard os.OpenFile is blocking. We accept this risk but log errors.
-	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
+	lj := &lumberjack.Logger{
+		Filename:   logPath,
+		MaxSize:    envInt("SEGI9_LOG_MAX_SIZE_MB", 100),
+		MaxAge:     envInt("SEGI9_LOG_MAX_AGE_DAYS", 0),
+		MaxBackups: envInt("SEGI9_LOG_MAX_BACKUPS", 0),
+		Compress:   envBool("SEGI9_LOG_COMPRESS", false),
+		LocalTime:  envBool("SEGI9_LOG_LOCAL_TIME", false),
+	}
+
+	// lumberjack opens the file lazily on first Write. Force that now, same
+	// as the old os.OpenFile call, so a bad path falls back to stderr
+	// immediately instead of on the first log line.
+	if _, err := lj.Write(nil); err != nil {
 		log.Printf("Failed to open log file %s: %v. Logging to stderr.", logPath, err)
 		return
 	}
 
-	log.SetOutput(f)
-	// We rely on OS to close the file on exit
+	logMu.Lock()
+	logWriter = lj
+	logMu.Unlock()
+	// Rotation is handled by lumberjack (size/age/backups/compress); the
+	// file itself is closed and reopened by lumberjack as needed.
+}
+
+// configureLogRotation applies the Plugins.Segi9.Logging.* conf values to the
+// rotating log writer, if any is open. It is a no-op when SEGI9_LOG_FILE was
+// not set, since there is no lumberjack.Logger to reconfigure.
+//
+// It swaps in a newly-constructed *lumberjack.Logger rather than mutating
+// the live one's exported fields in place: Write() reads those fields under
+// lumberjack's own unexported mutex, which has no relationship to logMu, so
+// mutating them directly races with concurrent log writes.
+func configureLogRotation(cfg LoggingConfig) {
+	logMu.Lock()
+	old := logWriter
+	if old == nil {
+		logMu.Unlock()
+		return
+	}
+
+	logWriter = &lumberjack.Logger{
+		Filename:   old.Filename,
+		MaxSize:    cfg.MaxSizeMB,
+		MaxAge:     cfg.MaxAgeDays,
+		MaxBackups: cfg.MaxBackups,
+		Compress:   cfg.Compress,
+		LocalTime:  cfg.LocalTime,
+	}
+	logMu.Unlock()
+
+	// Close the now-retired instance's file handle. Safe to do without
+	// coordinating with in-flight writes through it: Close and Write both
+	// lock lumberjack's own mutex on that instance.
+	if err := old.Close(); err != nil {
+		log.Printf("Failed to close previous log file handle: %v", err)
+	}
+}
+
+// watchRotateSignal lets admins force a log rollover (e.g. after external
+// log rotation moved the file aside) by sending SIGHUP, without restarting
+// the agent or the plugin process.
+func watchRotateSignal() {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+
+	go func() {
+		for range sigCh {
+			logMu.Lock()
+			lw := logWriter
+			logMu.Unlock()
+
+			if lw == nil {
+				continue
+			}
+			if err := lw.Rotate(); err != nil {
+				log.Printf("Failed to rotate log file on SIGHUP: %v", err)
+			} else {
+				log.Printf("Log file rotated via SIGHUP")
+			}
+		}
+	}()
+}
+
+// envInt reads an integer environment variable, falling back to def when the
+// variable is unset or not a valid integer.
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Invalid value for %s=%q, using default %d", name, v, def)
+		return def
+	}
+	return n
+}
+
+// envBool reads a boolean environment variable, falling back to def when the
+// variable is unset or not a valid boolean.
+func envBool(name string, def bool) bool {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("Invalid value for %s=%q, using default %v", name, v, def)
+		return def
+	}
+	return b
 }
 
 func cleanupSocket(socket string) {
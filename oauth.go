@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauthSafetyWindow is subtracted from a token's reported expires_in so we
+// refresh slightly before the authorization server would reject it.
+const oauthSafetyWindow = 30 * time.Second
+
+// oauthTokenCacheKey identifies one cached token by the triple that
+// determines it: where it came from, who asked, and what scope was
+// requested.
+type oauthTokenCacheKey struct {
+	tokenURL string
+	clientID string
+	scope    string
+}
+
+// oauthToken is a cached access token plus its expiry.
+type oauthToken struct {
+	accessToken string
+	expiresAt   time.Time
+}
+
+// oauthTokenCache caches client-credentials tokens across Export calls so we
+// don't trade a fresh token for every single Zabbix check cycle.
+var oauthTokenCache sync.Map // oauthTokenCacheKey -> oauthToken
+
+// oidcConfigCache caches the discovered token_endpoint per issuer so the
+// well-known document is only fetched once.
+var oidcConfigCache sync.Map // issuer string -> token_endpoint string
+
+// httpClientForTokens is reused for all token-endpoint and OIDC discovery
+// calls so connection pooling/keep-alives work across Export calls. It is
+// rebuilt by configureOAuthCACert when Plugins.Segi9.OAuth.CACertFile
+// changes.
+var (
+	tokenClientMu       sync.RWMutex
+	httpClientForTokens = &http.Client{Timeout: 10 * time.Second}
+)
+
+func tokenHTTPClient() *http.Client {
+	tokenClientMu.RLock()
+	defer tokenClientMu.RUnlock()
+	return httpClientForTokens
+}
+
+// configureOAuthCACert rebuilds the TLS trust store used for OAuth2/OIDC
+// token-endpoint calls, so a private CA can be trusted without touching the
+// system-wide trust store. An empty caFile restores the default client.
+func configureOAuthCACert(caFile string) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+
+	if caFile != "" {
+		pool, err := loadCACertPool(caFile)
+		if err != nil {
+			return err
+		}
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	}
+
+	tokenClientMu.Lock()
+	httpClientForTokens = client
+	tokenClientMu.Unlock()
+	return nil
+}
+
+// loadCACertPool reads a PEM-encoded CA bundle from disk into a cert pool.
+func loadCACertPool(caFile string) (*x509.CertPool, error) {
+	pemBytes, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %q: %w", caFile, err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemBytes) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %q", caFile)
+	}
+	return pool, nil
+}
+
+// fetchOAuth2Token returns a cached, still-valid access token for
+// (tokenURL, clientID, scope), acquiring (or, with force, refreshing) a new
+// one via RFC 6749 client-credentials when needed.
+func fetchOAuth2Token(tokenURL, clientID, clientSecret, scope string, force bool) (string, error) {
+	key := oauthTokenCacheKey{tokenURL: tokenURL, clientID: clientID, scope: scope}
+
+	if !force {
+		if cached, ok := oauthTokenCache.Load(key); ok {
+			tok := cached.(oauthToken)
+			if time.Now().Before(tok.expiresAt) {
+				return tok.accessToken, nil
+			}
+		}
+	}
+
+	tok, err := requestClientCredentialsToken(tokenURL, clientID, clientSecret, scope)
+	if err != nil {
+		return "", err
+	}
+
+	oauthTokenCache.Store(key, tok)
+	return tok.accessToken, nil
+}
+
+// requestClientCredentialsToken performs the RFC 6749 client-credentials
+// grant against tokenURL and returns the issued token.
+func requestClientCredentialsToken(tokenURL, clientID, clientSecret, scope string) (oauthToken, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if scope != "" {
+		form.Set("scope", strings.ReplaceAll(scope, ",", " "))
+	}
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return oauthToken{}, fmt.Errorf("failed to build token request for %q: %w", tokenURL, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+
+	resp, err := tokenHTTPClient().Do(req)
+	if err != nil {
+		return oauthToken{}, fmt.Errorf("token request to %q failed: %w", tokenURL, err)
+	}
+	defer resp.Body.Close()
+
+	var payload struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return oauthToken{}, fmt.Errorf("failed to decode token response from %q: %w", tokenURL, err)
+	}
+
+	if resp.StatusCode >= 400 || payload.AccessToken == "" {
+		return oauthToken{}, fmt.Errorf(
+			"token request to %q returned HTTP %d with no access_token", tokenURL, resp.StatusCode,
+		)
+	}
+
+	expiresIn := time.Duration(payload.ExpiresIn) * time.Second
+	return oauthToken{
+		accessToken: payload.AccessToken,
+		expiresAt:   time.Now().Add(expiresIn - oauthSafetyWindow),
+	}, nil
+}
+
+// discoverOIDCTokenEndpoint fetches <issuer>/.well-known/openid-configuration
+// and returns its token_endpoint, caching the result per issuer.
+func discoverOIDCTokenEndpoint(issuer string) (string, error) {
+	if cached, ok := oidcConfigCache.Load(issuer); ok {
+		return cached.(string), nil
+	}
+
+	discoveryURL := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+
+	resp, err := tokenHTTPClient().Get(discoveryURL)
+	if err != nil {
+		return "", fmt.Errorf("OIDC discovery at %q failed: %w", discoveryURL, err)
+	}
+	defer resp.Body.Close()
+
+	var doc struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return "", fmt.Errorf("failed to decode OIDC discovery document from %q: %w", discoveryURL, err)
+	}
+	if doc.TokenEndpoint == "" {
+		return "", fmt.Errorf("OIDC discovery document at %q has no token_endpoint", discoveryURL)
+	}
+
+	oidcConfigCache.Store(issuer, doc.TokenEndpoint)
+	return doc.TokenEndpoint, nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestFetchOAuth2Token(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "client-id" || pass != "client-secret" {
+			t.Errorf("unexpected basic auth: user=%q pass=%q ok=%v", user, pass, ok)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse token request form: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "client_credentials" {
+			t.Errorf("grant_type = %q, want client_credentials", got)
+		}
+		if got := r.PostForm.Get("scope"); got != "read write" {
+			t.Errorf("scope = %q, want %q", got, "read write")
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-1",
+			"expires_in":   3600,
+		})
+	}))
+	defer srv.Close()
+
+	token, err := fetchOAuth2Token(srv.URL, "client-id", "client-secret", "read,write", false)
+	if err != nil {
+		t.Fatalf("fetchOAuth2Token returned error: %v", err)
+	}
+	if token != "token-1" {
+		t.Fatalf("token = %q, want %q", token, "token-1")
+	}
+
+	// A second call for the same (tokenURL, clientID, scope) should be served
+	// from the cache, not hit the token endpoint again.
+	if _, err := fetchOAuth2Token(srv.URL, "client-id", "client-secret", "read,write", false); err != nil {
+		t.Fatalf("cached fetchOAuth2Token returned error: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("token endpoint called %d times, want 1 (second call should hit the cache)", requests)
+	}
+
+	// force=true must bypass the cache and fetch a fresh token.
+	if _, err := fetchOAuth2Token(srv.URL, "client-id", "client-secret", "read,write", true); err != nil {
+		t.Fatalf("forced fetchOAuth2Token returned error: %v", err)
+	}
+	if requests != 2 {
+		t.Fatalf("token endpoint called %d times, want 2 after a forced refresh", requests)
+	}
+}
+
+func TestFetchOAuth2TokenErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+	}))
+	defer srv.Close()
+
+	if _, err := fetchOAuth2Token(srv.URL, "client-id", "wrong-secret", "", false); err == nil {
+		t.Fatal("expected an error for a token endpoint returning HTTP 401")
+	}
+}
+
+func TestDiscoverOIDCTokenEndpoint(t *testing.T) {
+	var tokenEndpoint string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			t.Errorf("unexpected discovery path: %s", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token_endpoint": tokenEndpoint})
+	}))
+	defer srv.Close()
+	tokenEndpoint = srv.URL + "/token"
+
+	endpoint, err := discoverOIDCTokenEndpoint(srv.URL)
+	if err != nil {
+		t.Fatalf("discoverOIDCTokenEndpoint returned error: %v", err)
+	}
+	if endpoint != tokenEndpoint {
+		t.Fatalf("token endpoint = %q, want %q", endpoint, tokenEndpoint)
+	}
+}
+
+func TestDiscoverOIDCTokenEndpointMissing(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{})
+	}))
+	defer srv.Close()
+
+	if _, err := discoverOIDCTokenEndpoint(srv.URL); err == nil {
+		t.Fatal("expected an error when the discovery document has no token_endpoint")
+	}
+}
+
+func TestLoadCACertPool(t *testing.T) {
+	if _, err := loadCACertPool("/nonexistent/ca.pem"); err == nil {
+		t.Fatal("expected an error for a missing CA bundle path")
+	}
+
+	dir := t.TempDir()
+	badPath := dir + "/not-a-cert.pem"
+	if err := os.WriteFile(badPath, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("failed to write test fixture: %v", err)
+	}
+	if _, err := loadCACertPool(badPath); err == nil {
+		t.Fatal("expected an error for a CA bundle with no certificates")
+	}
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
-	"crypto/tls"
+	"bytes"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -31,9 +32,22 @@ var (
 
 // Plugin is the main struct for the Segi9 HTTP plugin.
 type Plugin struct {
-	plugin.Base        // Provides the Logger field and base Accessor methods.
+	plugin.Base // Provides the Logger field and base Accessor methods.
 	mu          sync.RWMutex
 	config      Config
+	transport   *http.Transport // built from config.TLS/SkipVerify, reused across Export calls
+	regexCache  sync.Map        // pattern string -> *regexp.Regexp, for return_mode=regex
+}
+
+// impl is the single Plugin instance registered with the Zabbix agent and
+// driven from main.go in both plugin and manual mode.
+var impl Plugin
+
+func init() {
+	plugin.RegisterMetrics(&impl, "Segi9",
+		"segi9.http", "Make HTTP/HTTPS requests to any reachable service and return JSON status.",
+		"segi9.http.discovery", "LLD: list endpoints from a segi9.http.discovery source file as {#NAME}/{#URL} pairs.",
+		"segi9.http.bulk", "Concurrently fetch every endpoint in a discovery group and return their status/hash/duration.")
 }
 
 // Config holds all configurable options for the plugin.
@@ -41,41 +55,81 @@ type Plugin struct {
 //
 //	Plugins.Segi9.Timeout=<1..30>
 //	Plugins.Segi9.SkipVerify=<true|false>
+//	Plugins.Segi9.ReturnBodyOnError=<true|false>
+//	Plugins.Segi9.OAuth.CACertFile=<path>
+//	Plugins.Segi9.TLS.*
+//	Plugins.Segi9.Logging.*
+//	Plugins.Segi9.MaxConcurrency=<int>
+//	Plugins.Segi9.Discovery.File=<path>
 type Config struct {
-	Timeout    int  `conf:"optional,range=1:30,default=10"`
-	SkipVerify bool `conf:"optional,default=false"`
+	Timeout           int             `conf:"optional,range=1:30,default=10"`
+	SkipVerify        bool            `conf:"optional,default=false"`
+	ReturnBodyOnError bool            `conf:"optional,default=false"`
+	MaxConcurrency    int             `conf:"optional,range=1:64,default=5"`
+	OAuth             OAuthConfig     `conf:"optional"`
+	TLS               TLSConfig       `conf:"optional"`
+	Logging           LoggingConfig   `conf:"optional"`
+	Discovery         DiscoveryConfig `conf:"optional"`
 }
 
-// ---------------------------------------------------------------------------
-// Logging helpers — nil-safe wrappers around plugin.Base.Logger.
-//
-// When running in manual mode (no Zabbix agent), p.Logger is nil and calling
-// the base methods directly would panic. These helpers fall back to the
-// standard library log package.
-// ---------------------------------------------------------------------------
+// OAuthConfig configures the HTTP client used for oauth2/oidc token-endpoint
+// and OIDC discovery calls (separate from the Plugins.Segi9.TLS.* settings,
+// which apply to the monitored target itself).
+type OAuthConfig struct {
+	CACertFile string `conf:"optional"`
+}
 
-func (p *Plugin) logInfof(format string, args ...interface{}) {
-	if p.Logger != nil {
-		p.Logger.Infof(format, args...)
-	} else {
-		log.Printf("[INFO]  "+format, args...)
-	}
+// TLSConfig configures mutual TLS and a custom CA bundle for requests to the
+// monitored target:
+//
+//	Plugins.Segi9.TLS.CertFile=<path>
+//	Plugins.Segi9.TLS.KeyFile=<path>
+//	Plugins.Segi9.TLS.CAFile=<path>
+//	Plugins.Segi9.TLS.ServerName=<hostname>
+//
+// CertFile and KeyFile must be set together to present a client certificate.
+// ServerName overrides SNI/verification when the URL's host doesn't match
+// the certificate (e.g. connecting through an IP or internal hostname).
+type TLSConfig struct {
+	CertFile   string `conf:"optional"`
+	KeyFile    string `conf:"optional"`
+	CAFile     string `conf:"optional"`
+	ServerName string `conf:"optional"`
 }
 
-func (p *Plugin) logDebugf(format string, args ...interface{}) {
-	if p.Logger != nil {
-		p.Logger.Debugf(format, args...)
-	} else {
-		log.Printf("[DEBUG] "+format, args...)
-	}
+// LoggingConfig controls both the structured logging backend (Format,
+// Level) and the rotating log file that plugin mode opens when
+// SEGI9_LOG_FILE is set (manual mode logs to stderr only):
+//
+//	Plugins.Segi9.Logging.Format=<text|json|logfmt>
+//	Plugins.Segi9.Logging.Level=<debug|info|warn|error>
+//	Plugins.Segi9.Logging.MaxSizeMB=<int>
+//	Plugins.Segi9.Logging.MaxAgeDays=<int>
+//	Plugins.Segi9.Logging.MaxBackups=<int>
+//	Plugins.Segi9.Logging.Compress=<true|false>
+//	Plugins.Segi9.Logging.LocalTime=<true|false>
+//
+// The rotation fields (env equivalents: SEGI9_LOG_MAX_SIZE_MB,
+// SEGI9_LOG_MAX_AGE_DAYS, SEGI9_LOG_MAX_BACKUPS, SEGI9_LOG_COMPRESS,
+// SEGI9_LOG_LOCAL_TIME) only take effect when SEGI9_LOG_FILE is set; they
+// have nothing to reconfigure otherwise.
+type LoggingConfig struct {
+	Format     string `conf:"optional,default=text"`
+	Level      string `conf:"optional,default=info"`
+	MaxSizeMB  int    `conf:"optional,default=100"`
+	MaxAgeDays int    `conf:"optional,default=0"`
+	MaxBackups int    `conf:"optional,default=0"`
+	Compress   bool   `conf:"optional,default=false"`
+	LocalTime  bool   `conf:"optional,default=false"`
 }
 
-func (p *Plugin) logErrf(format string, args ...interface{}) {
-	if p.Logger != nil {
-		p.Logger.Errf(format, args...)
-	} else {
-		log.Printf("[ERROR] "+format, args...)
-	}
+// DiscoveryConfig points segi9.http.discovery and segi9.http.bulk at the
+// endpoint list they read when the item key itself doesn't supply one.
+// segi9.http.discovery[<file>] accepts an override as its own parameter;
+// segi9.http.bulk[<group_name>] always reads from File, since its parameter
+// is the group to select, not a path.
+type DiscoveryConfig struct {
+	File string `conf:"optional"`
 }
 
 // ---------------------------------------------------------------------------
@@ -84,12 +138,18 @@ func (p *Plugin) logErrf(format string, args ...interface{}) {
 
 // Start is called once by the Zabbix agent when the plugin process initialises.
 func (p *Plugin) Start() {
-	p.logInfof("Segi9 HTTP plugin started")
+	p.logEvent(levelInfo, "Segi9 HTTP plugin started")
 }
 
 // Stop is called by the Zabbix agent before the plugin process is terminated.
 func (p *Plugin) Stop() {
-	p.logInfof("Segi9 HTTP plugin stopped")
+	p.logEvent(levelInfo, "Segi9 HTTP plugin stopped")
+}
+
+// Name returns the plugin name used to register the agent's RPC handler
+// (see container.NewHandler in main.go).
+func (p *Plugin) Name() string {
+	return "Segi9"
 }
 
 // ---------------------------------------------------------------------------
@@ -100,18 +160,20 @@ func (p *Plugin) Stop() {
 // global contains the agent-wide settings (e.g. global timeout).
 // options contains the plugin-specific settings from the conf file.
 func (p *Plugin) Configure(global *plugin.GlobalOptions, options interface{}) {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	// Reset to built-in defaults before applying new values.
-	p.config = Config{
-		Timeout:    10,
-		SkipVerify: false,
+	// Assembled without holding p.mu: logEvent takes p.mu.RLock() to read the
+	// *current* Logging config, and sync.RWMutex isn't reentrant, so calling
+	// it while this goroutine already holds p.mu.Lock() would deadlock.
+	cfg := Config{
+		Timeout:           10,
+		SkipVerify:        false,
+		ReturnBodyOnError: false,
+		MaxConcurrency:    5,
+		Logging:           LoggingConfig{Format: "text", Level: "info", MaxSizeMB: 100},
 	}
 
 	if options != nil {
-		if err := conf.Unmarshal(options, &p.config); err != nil {
-			p.logErrf("failed to parse plugin configuration: %v", err)
+		if err := conf.Unmarshal(options, &cfg); err != nil {
+			p.logEvent(levelError, "failed to parse plugin configuration", f("error", err))
 			// Keep the defaults already set above and carry on.
 		}
 	}
@@ -119,29 +181,56 @@ func (p *Plugin) Configure(global *plugin.GlobalOptions, options interface{}) {
 	// Use the global agent timeout as a fallback only if the plugin timeout
 	// ended up at zero (which should not happen with the default=10 tag,
 	// but we guard against it just in case).
-	if p.config.Timeout == 0 && global != nil && global.Timeout > 0 {
-		p.config.Timeout = global.Timeout
+	if cfg.Timeout == 0 && global != nil && global.Timeout > 0 {
+		cfg.Timeout = global.Timeout
 	}
 
 	// Hard clamp — ensures the value is always in a sane range regardless of
 	// what the conf tag parser returned.
-	if p.config.Timeout < 1 {
-		p.config.Timeout = 1
+	if cfg.Timeout < 1 {
+		cfg.Timeout = 1
+	}
+	if cfg.Timeout > 30 {
+		cfg.Timeout = 30
 	}
-	if p.config.Timeout > 30 {
-		p.config.Timeout = 30
+
+	if cfg.MaxConcurrency < 1 {
+		cfg.MaxConcurrency = 1
 	}
 
-	p.logInfof("configuration applied: Timeout=%ds SkipVerify=%v",
-		p.config.Timeout, p.config.SkipVerify)
+	configureLogRotation(cfg.Logging)
+
+	if err := configureOAuthCACert(cfg.OAuth.CACertFile); err != nil {
+		p.logEvent(levelError, "failed to load OAuth CA bundle", f("error", err))
+	}
+
+	transport, transportErr := buildTargetTransport(cfg)
+	if transportErr != nil {
+		p.logEvent(levelError, "failed to build TLS transport, keeping previous one", f("error", transportErr))
+	}
+
+	// Swap p.config/p.transport in under a single, short-lived write lock so
+	// a concurrent Export call never observes a half-applied config.
+	p.mu.Lock()
+	p.config = cfg
+	if transportErr == nil {
+		p.transport = transport
+	}
+	p.mu.Unlock()
+
+	p.logEvent(levelInfo, "configuration applied",
+		f("timeout_s", cfg.Timeout), f("skip_verify", cfg.SkipVerify))
 }
 
 // Validate is called by the Zabbix agent before applying a new configuration
 // to verify that the values are acceptable. Return a non-nil error to reject.
 func (p *Plugin) Validate(options interface{}) error {
 	cfg := Config{
-		Timeout:    10, // safe default if options is nil
-		SkipVerify: false,
+		Timeout:           10, // safe default if options is nil
+		SkipVerify:        false,
+		ReturnBodyOnError: false,
+		MaxConcurrency:    5,
+		Logging:           LoggingConfig{Format: "text", Level: "info", MaxSizeMB: 100},
 	}
 
 	if options != nil {
@@ -157,6 +246,16 @@ func (p *Plugin) Validate(options interface{}) error {
 		)
 	}
 
+	if _, err := buildTargetTLSConfig(cfg); err != nil {
+		return errs.Wrap(err, "invalid Plugins.Segi9.TLS configuration")
+	}
+
+	if cfg.OAuth.CACertFile != "" {
+		if _, err := loadCACertPool(cfg.OAuth.CACertFile); err != nil {
+			return errs.Wrap(err, "invalid Plugins.Segi9.OAuth.CACertFile")
+		}
+	}
+
 	return nil
 }
 
@@ -168,18 +267,41 @@ func (p *Plugin) Validate(options interface{}) error {
 //
 // Item key signature:
 //
-//	segi9.http[<url>, <auth_type>, <user_or_token>, <password>]
+//	segi9.http[<url>, <auth_type>, <user_or_token>, <password>, <method>, <body>, <headers>, <timeout>, <token_url>, <scope>, <return_mode>, <expr>]
 //
 // Parameters:
 //
 //	url          (required) – target URL, e.g. https://api.exemplo.com/status
-//	auth_type    (optional) – none (default) | basic | bearer
-//	user_or_token(optional) – username (basic) or bearer token (bearer)
-//	password     (optional) – password (basic only)
+//	auth_type    (optional) – none (default) | basic | bearer | oauth2 | oidc
+//	user_or_token(optional) – username (basic) / bearer token (bearer) / client_id (oauth2, oidc)
+//	password     (optional) – password (basic) / client_secret (oauth2, oidc)
+//	method       (optional) – GET (default) | POST | PUT | PATCH | DELETE | HEAD
+//	body         (optional) – raw request body; prefix with "b64:" for binary payloads
+//	headers      (optional) – "Key: Value" pairs separated by newlines or "|"
+//	timeout      (optional) – per-call timeout in seconds, overrides Plugins.Segi9.Timeout (still clamped to 1..30)
+//	token_url    (oauth2/oidc) – the token endpoint (oauth2) or issuer to discover it from (oidc)
+//	scope        (oauth2/oidc) – comma-separated OAuth2 scopes, optional
+//	return_mode  (optional) – body (default) | status | duration_ms | header | jsonpath | regex
+//	expr         (header/jsonpath/regex) – header name, JSONPath expression, or regex pattern (first capture group)
+//
+// Only url is required, so existing items created before the later
+// parameters were added keep working unchanged.
+//
+// Returns the raw HTTP response body as a string, unless return_mode selects
+// something else (see above).
 //
-// Returns the raw HTTP response body as a string.
-func (p *Plugin) Export(key string, params []string, _ plugin.ContextProvider) (interface{}, error) {
-	if key != "segi9.http" {
+// segi9.http.discovery[<file>] and segi9.http.bulk[<group_name>] are
+// low-frequency discovery/fan-out keys layered on top of the same endpoint
+// list; see discovery.go.
+func (p *Plugin) Export(key string, params []string, ctx plugin.ContextProvider) (interface{}, error) {
+	switch key {
+	case "segi9.http.discovery":
+		return p.exportDiscovery(paramAt(params, 0))
+	case "segi9.http.bulk":
+		return p.exportBulk(paramAt(params, 0))
+	case "segi9.http":
+		// falls through to the request logic below
+	default:
 		return nil, errs.Errorf("unsupported key: %q", key)
 	}
 
@@ -188,106 +310,331 @@ func (p *Plugin) Export(key string, params []string, _ plugin.ContextProvider) (
 		return nil, fmt.Errorf("segi9.http: the first parameter (url) is required and cannot be empty")
 	}
 
-	url := strings.TrimSpace(params[0])
+	spec := httpRequestSpec{
+		url: strings.TrimSpace(params[0]),
+		key: key,
+		ctx: ctx,
+	}
 
-	authType := "none"
+	spec.authType = "none"
 	if len(params) > 1 && strings.TrimSpace(params[1]) != "" {
-		authType = strings.TrimSpace(params[1])
+		spec.authType = strings.TrimSpace(params[1])
 	}
 
-	var user, pass string
 	if len(params) > 2 {
-		user = params[2]
+		spec.user = params[2]
 	}
 	if len(params) > 3 {
-		pass = params[3]
+		spec.pass = params[3]
+	}
+
+	method, err := parseHTTPMethod(paramAt(params, 4))
+	if err != nil {
+		return nil, fmt.Errorf("segi9.http: %w", err)
+	}
+	spec.method = method
+
+	spec.body = paramAt(params, 5)
+
+	spec.headers = paramAt(params, 6)
+
+	if seconds, ok, err := parseTimeoutOverride(paramAt(params, 7)); err != nil {
+		return nil, fmt.Errorf("segi9.http: %w", err)
+	} else if ok {
+		spec.timeout = time.Duration(seconds) * time.Second
+	}
+
+	spec.tokenURL = paramAt(params, 8)
+	spec.scope = paramAt(params, 9)
+
+	spec.returnMode = paramAt(params, 10)
+	spec.expr = paramAt(params, 11)
+	if err := validateReturnMode(spec.returnMode, spec.expr); err != nil {
+		return nil, fmt.Errorf("segi9.http: %w", err)
 	}
 
-	p.logDebugf("export: key=%s url=%q auth=%s", key, url, authType)
+	exportFields := []field{f(fieldKey, key), f(fieldURL, spec.url), f(fieldAuthType, spec.authType)}
+	if ctx != nil {
+		exportFields = append(exportFields, f(fieldItemID, ctx.ItemID()))
+	}
+	p.logEvent(levelDebug, "export", exportFields...)
 
-	return p.doRequest(url, authType, user, pass)
+	return p.doRequest(spec)
+}
+
+// paramAt returns params[i], or "" if the item key was called with fewer
+// than i+1 parameters.
+func paramAt(params []string, i int) string {
+	if i < len(params) {
+		return params[i]
+	}
+	return ""
 }
 
 // ---------------------------------------------------------------------------
 // Core HTTP logic — shared by Export() and runManual().
 // ---------------------------------------------------------------------------
 
-// doRequest performs an HTTP GET request with the given authentication and
-// returns the full response body as a string.
-func (p *Plugin) doRequest(url, authType, user, pass string) (string, error) {
+// httpRequestSpec bundles everything doRequest needs for one HTTP call: the
+// target and payload, authentication, and the logging context (key/ctx) of
+// the item that triggered it. key and ctx may be "" / nil, e.g. when called
+// from manual mode.
+type httpRequestSpec struct {
+	url        string
+	authType   string
+	user       string
+	pass       string
+	method     string
+	body       string
+	headers    string
+	timeout    time.Duration // 0 means "use the plugin-configured default"
+	tokenURL   string        // oauth2: the token endpoint; oidc: the issuer to discover it from
+	scope      string        // oauth2/oidc: comma-separated scopes
+	returnMode string        // "" (body, default) | status | duration_ms | header | jsonpath | regex
+	expr       string        // header name / JSONPath expression / regex pattern, per returnMode
+	key        string
+	ctx        plugin.ContextProvider
+}
+
+// doRequest performs the HTTP call described by spec and returns, as a
+// string, the response body or whatever spec.returnMode selects instead
+// (status code, duration, header value, JSONPath or regex match).
+func (p *Plugin) doRequest(spec httpRequestSpec) (string, error) {
+	start := time.Now()
+
 	// Read config under a shared (read) lock so we don't block other goroutines.
 	p.mu.RLock()
 	timeout := time.Duration(p.config.Timeout) * time.Second
-	skipVerify := p.config.SkipVerify
+	returnBodyOnError := p.config.ReturnBodyOnError
+	transport := p.transport
 	p.mu.RUnlock()
 
+	if spec.timeout > 0 {
+		timeout = spec.timeout
+	}
+
 	// Safety net in case we are called before Configure (e.g. in manual mode
 	// with an uninitialised config — though runManual sets the config directly).
 	if timeout <= 0 {
 		timeout = 10 * time.Second
 	}
+	if transport == nil {
+		transport, _ = buildTargetTransport(p.config) // best-effort fallback, ignores InsecureSkipVerify mismatch
+	}
 
-	// Build the HTTP client.
+	// The transport (and the TLS/connection state it pools) is built once in
+	// Configure and reused here; only the per-call timeout varies.
 	client := &http.Client{
-		Timeout: timeout,
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: skipVerify, //nolint:gosec // user-controlled via Plugins.Segi9.SkipVerify
-			},
-		},
+		Timeout:   timeout,
+		Transport: transport,
 	}
 
-	// Build the request.
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+	bodyBytes, err := decodeRequestBody(spec.body)
 	if err != nil {
-		return "", fmt.Errorf("failed to build HTTP request for %q: %w", url, err)
+		return "", fmt.Errorf("segi9.http: %w", err)
 	}
 
-	req.Header.Set("User-Agent", "Zabbix-Plugin-Segi9/1.0")
-	req.Header.Set("Accept", "*/*")
+	headers, err := parseHeaders(spec.headers)
+	if err != nil {
+		return "", fmt.Errorf("segi9.http: %w", err)
+	}
 
-	// Apply authentication.
-	switch strings.ToLower(authType) {
+	authType := strings.ToLower(spec.authType)
+	oauthLike := authType == "oauth2" || authType == "oidc"
 
-	case "basic":
-		// user = username, pass = password
-		req.SetBasicAuth(user, pass)
+	// buildRequest constructs one attempt. It is called again, with a fresh
+	// body reader and a freshly-acquired token, if the first attempt comes
+	// back 401 for oauth2/oidc.
+	buildRequest := func(bearerToken string) (*http.Request, error) {
+		var bodyReader io.Reader
+		if bodyBytes != nil {
+			bodyReader = bytes.NewReader(bodyBytes)
+		}
 
-	case "bearer":
-		// user = the bearer token (pass is ignored)
-		if user == "" {
-			return "", fmt.Errorf("auth_type 'bearer' requires a token in the third parameter (user)")
+		req, err := http.NewRequest(spec.method, spec.url, bodyReader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build HTTP request for %q: %w", spec.url, err)
 		}
-		req.Header.Set("Authorization", "Bearer "+user)
 
-	case "none", "":
-		// No authentication — nothing to add.
+		req.Header.Set("User-Agent", "Zabbix-Plugin-Segi9/1.0")
+		req.Header.Set("Accept", "*/*")
+		for _, h := range headers {
+			req.Header.Set(h[0], h[1])
+		}
+
+		switch authType {
+
+		case "basic":
+			// user = username, pass = password
+			req.SetBasicAuth(spec.user, spec.pass)
+
+		case "bearer":
+			// user = the bearer token (pass is ignored)
+			if spec.user == "" {
+				return nil, fmt.Errorf("auth_type 'bearer' requires a token in the third parameter (user)")
+			}
+			req.Header.Set("Authorization", "Bearer "+spec.user)
 
+		case "oauth2", "oidc":
+			req.Header.Set("Authorization", "Bearer "+bearerToken)
+
+		case "none", "":
+			// No authentication — nothing to add.
+
+		default:
+			return nil, fmt.Errorf(
+				"unsupported auth_type %q; valid values are: none, basic, bearer, oauth2, oidc",
+				spec.authType,
+			)
+		}
+
+		return req, nil
+	}
+
+	var bearerToken string
+	if oauthLike {
+		if bearerToken, err = p.oauthBearerToken(spec, false); err != nil {
+			return "", err
+		}
+	}
+
+	p.logEvent(levelDebug, "request", f(fieldURL, spec.url), f(fieldAuthType, spec.authType))
+
+	req, err := buildRequest(bearerToken)
+	if err != nil {
+		return "", err
+	}
+
+	resp, respBody, err := execRequest(client, req)
+	if err != nil {
+		return "", fmt.Errorf("HTTP request to %q failed: %w", spec.url, err)
+	}
+
+	// Per RFC 6749 §5.2, a 401 from the resource server can mean the token
+	// expired early; refresh once and retry rather than failing the check.
+	if oauthLike && resp.StatusCode == http.StatusUnauthorized {
+		if bearerToken, err = p.oauthBearerToken(spec, true); err != nil {
+			return "", err
+		}
+		if req, err = buildRequest(bearerToken); err != nil {
+			return "", err
+		}
+		if resp, respBody, err = execRequest(client, req); err != nil {
+			return "", fmt.Errorf("HTTP request to %q failed: %w", spec.url, err)
+		}
+	}
+
+	duration := time.Since(start)
+
+	respFields := []field{
+		f(fieldURL, spec.url),
+		f(fieldStatus, resp.StatusCode),
+		f(fieldBytes, len(respBody)),
+		f(fieldDurationMS, duration.Milliseconds()),
+	}
+	if spec.key != "" {
+		respFields = append(respFields, f(fieldKey, spec.key))
+	}
+	if spec.ctx != nil {
+		respFields = append(respFields, f(fieldItemID, spec.ctx.ItemID()))
+	}
+	p.logEvent(levelDebug, "response", respFields...)
+
+	// status/duration_ms are about the call itself, not its outcome, so they
+	// are returned even for a >=400 response.
+	switch strings.ToLower(spec.returnMode) {
+	case "status":
+		return strconv.Itoa(resp.StatusCode), nil
+	case "duration_ms":
+		return strconv.FormatFloat(duration.Seconds()*1000, 'f', 3, 64), nil
+	}
+
+	if resp.StatusCode >= 400 && !returnBodyOnError {
+		return "", fmt.Errorf("request to %q returned HTTP %d: %s", spec.url, resp.StatusCode, respBody)
+	}
+
+	switch strings.ToLower(spec.returnMode) {
+	case "", "body":
+		// Return the raw body. Zabbix pre-processing rules on the item can
+		// parse it further (JSONPath, regex, etc.) as needed.
+		return string(respBody), nil
+	case "header":
+		return resp.Header.Get(spec.expr), nil
+	case "jsonpath":
+		return extractJSONPath(respBody, spec.expr)
+	case "regex":
+		return p.extractRegex(spec.expr, respBody)
 	default:
-		return "", fmt.Errorf(
-			"unsupported auth_type %q; valid values are: none, basic, bearer",
-			authType,
-		)
+		return "", fmt.Errorf("unsupported return_mode %q", spec.returnMode)
+	}
+}
+
+// extractRegex runs pattern's first capture group against body, compiling
+// and caching the regex on the Plugin so repeated calls with the same
+// pattern (the common case — items reuse one return_mode/expr) don't pay to
+// recompile it every check cycle.
+func (p *Plugin) extractRegex(pattern string, body []byte) (string, error) {
+	var re *regexp.Regexp
+	if cached, ok := p.regexCache.Load(pattern); ok {
+		re = cached.(*regexp.Regexp)
+	} else {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("regex %q: %w", pattern, err)
+		}
+		re = compiled
+		p.regexCache.Store(pattern, re)
 	}
 
-	p.logDebugf("→ GET %s (timeout=%v tls_skip=%v)", url, timeout, skipVerify)
+	m := re.FindSubmatch(body)
+	if m == nil {
+		return "", fmt.Errorf("regex %q did not match the response body", pattern)
+	}
+	if len(m) < 2 {
+		return "", fmt.Errorf("regex %q has no capture group", pattern)
+	}
+	return string(m[1]), nil
+}
 
-	// Execute the request.
+// execRequest runs req and drains its body, since the caller may need to
+// retry and a response whose body wasn't read can't be reused or closed
+// cleanly.
+func execRequest(client *http.Client, req *http.Request) (*http.Response, []byte, error) {
 	resp, err := client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("HTTP request to %q failed: %w", url, err)
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
-	// Read the entire response body.
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body from %q: %w", url, err)
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
 	}
+	return resp, body, nil
+}
 
-	p.logDebugf("← %s (%d bytes)", resp.Status, len(body))
+// oauthBearerToken resolves the bearer token for spec's oauth2/oidc
+// auth_type: client_id/client_secret come from spec.user/spec.pass, and
+// spec.tokenURL is either the token endpoint directly (oauth2) or the
+// issuer to discover it from (oidc). force bypasses the token cache, used
+// to refresh after a 401.
+func (p *Plugin) oauthBearerToken(spec httpRequestSpec, force bool) (string, error) {
+	if spec.tokenURL == "" {
+		return "", fmt.Errorf("auth_type %q requires a token_url parameter", spec.authType)
+	}
 
-	// Return the raw body. Zabbix pre-processing rules on the item can parse
-	// it further (JSONPath, regex, etc.) as needed.
-	return string(body), nil
+	tokenURL := spec.tokenURL
+	if strings.ToLower(spec.authType) == "oidc" {
+		endpoint, err := discoverOIDCTokenEndpoint(spec.tokenURL)
+		if err != nil {
+			return "", fmt.Errorf("oidc discovery failed: %w", err)
+		}
+		tokenURL = endpoint
+	}
+
+	token, err := fetchOAuth2Token(tokenURL, spec.user, spec.pass, spec.scope, force)
+	if err != nil {
+		return "", fmt.Errorf("%s token acquisition failed: %w", spec.authType, err)
+	}
+	return token, nil
 }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestConfigureDoesNotDeadlock guards against Configure logging (which takes
+// p.mu.RLock via logEvent) while still holding p.mu.Lock() on the same
+// goroutine — sync.RWMutex isn't reentrant, so that would hang forever
+// instead of returning an error.
+func TestConfigureDoesNotDeadlock(t *testing.T) {
+	p := &Plugin{}
+
+	done := make(chan struct{})
+	go func() {
+		p.Configure(nil, nil)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Configure did not return; likely deadlocked on p.mu")
+	}
+}
+
+func TestExtractRegex(t *testing.T) {
+	p := &Plugin{}
+	body := []byte(`status=ok code=42`)
+
+	got, err := p.extractRegex(`code=(\d+)`, body)
+	if err != nil {
+		t.Fatalf("extractRegex returned error: %v", err)
+	}
+	if got != "42" {
+		t.Fatalf("extractRegex = %q, want %q", got, "42")
+	}
+
+	// A second call with the same pattern should hit p.regexCache instead of
+	// recompiling it.
+	if _, ok := p.regexCache.Load(`code=(\d+)`); !ok {
+		t.Fatal("expected the compiled pattern to be cached after the first call")
+	}
+	if _, err := p.extractRegex(`code=(\d+)`, body); err != nil {
+		t.Fatalf("cached extractRegex returned error: %v", err)
+	}
+}
+
+func TestExtractRegexNoMatch(t *testing.T) {
+	p := &Plugin{}
+	if _, err := p.extractRegex(`code=(\d+)`, []byte("status=ok")); err == nil {
+		t.Fatal("expected an error when the pattern does not match the body")
+	}
+}
+
+func TestExtractRegexNoCaptureGroup(t *testing.T) {
+	p := &Plugin{}
+	if _, err := p.extractRegex(`status`, []byte("status=ok")); err == nil {
+		t.Fatal("expected an error for a pattern with no capture group")
+	}
+}
+
+func TestExtractRegexInvalidPattern(t *testing.T) {
+	p := &Plugin{}
+	if _, err := p.extractRegex(`(`, []byte("status=ok")); err == nil {
+		t.Fatal("expected an error for an invalid regex pattern")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+)
+
+// buildTargetTLSConfig constructs the tls.Config used for requests to the
+// monitored target: SkipVerify, optional mTLS client certificate, optional
+// custom CA bundle, and optional SNI override.
+func buildTargetTLSConfig(cfg Config) (*tls.Config, error) {
+	tlsCfg := &tls.Config{
+		InsecureSkipVerify: cfg.SkipVerify, //nolint:gosec // user-controlled via Plugins.Segi9.SkipVerify
+		ServerName:         cfg.TLS.ServerName,
+	}
+
+	if cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != "" {
+		if cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "" {
+			return nil, fmt.Errorf("Plugins.Segi9.TLS: CertFile and KeyFile must both be set for mTLS")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"failed to load TLS client certificate (CertFile=%q, KeyFile=%q): %w",
+				cfg.TLS.CertFile, cfg.TLS.KeyFile, err,
+			)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.TLS.CAFile != "" {
+		pool, err := loadCACertPool(cfg.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Plugins.Segi9.TLS.CAFile: %w", err)
+		}
+		tlsCfg.RootCAs = pool
+	}
+
+	return tlsCfg, nil
+}
+
+// buildTargetTransport builds the *http.Transport reused across Export
+// calls, so TCP connections and TLS sessions to the same target are pooled
+// across checks instead of being torn down and rebuilt every time.
+func buildTargetTransport(cfg Config) (*http.Transport, error) {
+	tlsCfg, err := buildTargetTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Transport{TLSClientConfig: tlsCfg}, nil
+}